@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/minikube/pkg/minikube/registry/drvs/docker"
+)
+
+var preflightOutput string
+
+// preflightCmd checks whether a container runtime version passes minikube's KIC preflight checks,
+// without starting a cluster. It exists for automation (CI, IDE plugins, the VS Code Kubernetes
+// extension) that wants to react to the same checks `minikube start` runs, but programmatically.
+var preflightCmd = &cobra.Command{
+	Use:   "preflight RUNTIME VERSION",
+	Short: "Checks whether a container runtime version passes minikube's preflight checks",
+	Long: `preflight runs the same runtime version checks minikube performs before starting a cluster.
+VERSION is the "<os type>-<server version>" string reported by the runtime's info endpoint, e.g.
+"linux-20.10.5". Use --output=json to get a machine-readable Diagnostic instead of the default text
+summary.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runtime, versionString := args[0], args[1]
+
+		if runtime != "docker" {
+			return fmt.Errorf("unsupported runtime %q (only \"docker\" is supported today)", runtime)
+		}
+
+		if preflightOutput == "json" {
+			out, err := docker.StatusJSON(versionString)
+			if err != nil {
+				return fmt.Errorf("failed to render diagnostics: %w", err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(out))
+			return nil
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), docker.StatusText(versionString))
+		return nil
+	},
+}
+
+func init() {
+	preflightCmd.Flags().StringVar(&preflightOutput, "output", "text", "Output format. One of: text, json")
+	RootCmd.AddCommand(preflightCmd)
+}
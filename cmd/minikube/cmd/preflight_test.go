@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPreflightCmd(t *testing.T) {
+	tc := []struct {
+		name       string
+		args       []string
+		expectText string
+	}{
+		{
+			name:       "text output for a version below the minimum",
+			args:       []string{"docker", "linux-01.01.01"},
+			expectText: "PROVIDER_DOCKER_VERSION_LOW",
+		},
+		{
+			name:       "json output for a version below the minimum",
+			args:       []string{"docker", "linux-01.01.01", "--output=json"},
+			expectText: `"code":"PROVIDER_DOCKER_VERSION_LOW"`,
+		},
+	}
+
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			preflightCmd.SetOut(buf)
+			RootCmd.SetArgs(append([]string{"preflight"}, c.args...))
+			if err := RootCmd.Execute(); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			if !strings.Contains(buf.String(), c.expectText) {
+				t.Errorf("expected output to contain %q, got %q", c.expectText, buf.String())
+			}
+		})
+	}
+}
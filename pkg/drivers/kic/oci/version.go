@@ -0,0 +1,400 @@
+/*
+Copyright 2019 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/spf13/viper"
+
+	"k8s.io/minikube/pkg/minikube/driver"
+)
+
+// Runtime names accepted by checkRuntimeVersion.
+const (
+	Docker     = "docker"
+	Containerd = "containerd"
+	Podman     = "podman"
+)
+
+var (
+	// ErrWindowsContainers is thrown when docker is configured to run windows containers
+	ErrWindowsContainers = errors.New("running containers of windowstype is not supported")
+	// ErrMinDockerVersion is the error thrown when docker version is less than the minimum supported version
+	ErrMinDockerVersion = errors.New("docker version is less than the minimum version")
+	// ErrMinContainerdVersion is thrown when containerd version is less than the minimum supported version
+	ErrMinContainerdVersion = errors.New("containerd version is less than the minimum version")
+	// ErrMinPodmanVersion is thrown when podman version is less than the minimum supported version
+	ErrMinPodmanVersion = errors.New("podman version is less than the minimum version")
+	// ErrBadDockerVersion is thrown when docker matches a release known to be broken with KIC
+	ErrBadDockerVersion = errors.New("docker version is known to be incompatible with KIC")
+)
+
+// dockerBadVersionsEnv overrides dockerBadVersions with a comma-separated "min:max" range list
+// (e.g. "20.10.9:20.10.10,19.03.13:19.03.13"), or disables the blocklist entirely when set and empty.
+// This lets CI pin an otherwise-blocked Docker version during regression testing.
+const dockerBadVersionsEnv = "MINIKUBE_DOCKER_BAD_VERSIONS"
+
+// versionRange is an inclusive range of docker versions known to break KIC.
+type versionRange struct {
+	min    semver.Version
+	max    semver.Version
+	reason string
+}
+
+func (r versionRange) contains(v semver.Version) bool {
+	return !v.LT(r.min) && !v.GT(r.max)
+}
+
+// mustParseTolerant parses s with semver.ParseTolerant, which (unlike semver.MustParse) accepts the
+// zero-padded version segments ("03", "09") that Docker itself reports, and panics on failure. Only
+// meant for literals we control, such as the dockerBadVersions table below.
+func mustParseTolerant(s string) semver.Version {
+	v, err := semver.ParseTolerant(s)
+	if err != nil {
+		panic(fmt.Sprintf("oci: invalid hardcoded version %q: %v", s, err))
+	}
+	return v
+}
+
+// dockerBadVersions are docker releases known to break KIC, independent of the MinDockerVersion floor.
+var dockerBadVersions = defaultDockerBadVersions()
+
+func defaultDockerBadVersions() []versionRange {
+	if raw, ok := os.LookupEnv(dockerBadVersionsEnv); ok {
+		return parseDockerBadVersions(raw)
+	}
+
+	return []versionRange{
+		// broken cgroup v2 handling, fixed in 20.10.11.
+		{min: mustParseTolerant("20.10.9"), max: mustParseTolerant("20.10.10"), reason: "broken cgroup v2 handling"},
+		// iptables-nft regression that drops KIC port forwarding.
+		{min: mustParseTolerant("19.03.13"), max: mustParseTolerant("19.03.13"), reason: "iptables-nft regression"},
+	}
+}
+
+// parseDockerBadVersions parses the MINIKUBE_DOCKER_BAD_VERSIONS override. Malformed entries are
+// skipped rather than erroring, since this is a best-effort CI knob, not user-facing configuration.
+func parseDockerBadVersions(raw string) []versionRange {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	var ranges []versionRange
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		min, err := semver.ParseTolerant(strings.TrimSpace(parts[0]))
+		if err != nil {
+			continue
+		}
+		max, err := semver.ParseTolerant(strings.TrimSpace(parts[1]))
+		if err != nil {
+			continue
+		}
+
+		ranges = append(ranges, versionRange{min: min, max: max, reason: "blocked via " + dockerBadVersionsEnv})
+	}
+	return ranges
+}
+
+// gitDescribeSuffix matches the `git describe`-style suffix that `make binary` installs append to the
+// docker version string, e.g. "20.10.0-20180720214833-f61e0f7".
+var gitDescribeSuffix = regexp.MustCompile(`^\d{14}-[0-9a-f]{7,}$`)
+
+// MinDockerVersion is the minimum version of docker supported by KIC.
+var MinDockerVersion = []int{18, 9, 0}
+
+// MinContainerdVersion is the minimum version of containerd supported by KIC, tracking the 1.7.x series.
+var MinContainerdVersion = []int{1, 7, 0}
+
+// MinPodmanVersion is the minimum version of podman supported by KIC, tracking the rootless-by-default 4.x series.
+var MinPodmanVersion = []int{4, 0, 0}
+
+// State is the runtime health/version state returned by the runtime preflight checks.
+type State struct {
+	Installed bool
+	Healthy   bool
+	Running   bool
+	Error     error
+	Reason    string
+	Fix       string
+	Doc       string
+	// Diagnostic is a machine-readable counterpart to Fix, for automation (CI, IDE plugins, the
+	// VS Code Kubernetes extension) that wants to react programmatically instead of matching on Fix text.
+	// It is nil for a fully healthy State.
+	Diagnostic *Diagnostic
+}
+
+// Diagnostic is the structured form of a preflight problem found by a runtime version check.
+type Diagnostic struct {
+	Code           string `json:"code"`
+	Runtime        string `json:"runtime"`
+	Observed       string `json:"observed"`
+	Required       string `json:"required,omitempty"`
+	Recommendation string `json:"recommendation"`
+	DocsURL        string `json:"docsURL"`
+}
+
+// diagnosticDocsURL returns the docs page to point a Diagnostic at for runtime.
+func diagnosticDocsURL(runtime string) string {
+	return fmt.Sprintf("https://minikube.sigs.k8s.io/docs/drivers/%s/", runtime)
+}
+
+// RenderDiagnosticsJSON serializes the Diagnostic of every non-healthy State for `--output=json`
+// consumers. States without a Diagnostic (fully healthy runtimes) are omitted.
+func RenderDiagnosticsJSON(states ...State) ([]byte, error) {
+	diags := []Diagnostic{}
+	for _, s := range states {
+		if s.Diagnostic != nil {
+			diags = append(diags, *s.Diagnostic)
+		}
+	}
+	return json.Marshal(diags)
+}
+
+// runtimeTitle is the human readable name used in Fix messages for a runtime.
+func runtimeTitle(runtime string) string {
+	if runtime == Docker {
+		return driver.FullName(driver.Docker)
+	}
+	return strings.Title(runtime)
+}
+
+// minVersionFor returns the minimum supported version triplet, low-version Reason and error for runtime.
+func minVersionFor(runtime string) ([]int, string, error) {
+	switch runtime {
+	case Docker:
+		return MinDockerVersion, "PROVIDER_DOCKER_VERSION_LOW", ErrMinDockerVersion
+	case Containerd:
+		return MinContainerdVersion, "PROVIDER_CONTAINERD_VERSION_LOW", ErrMinContainerdVersion
+	case Podman:
+		return MinPodmanVersion, "PROVIDER_PODMAN_VERSION_LOW", ErrMinPodmanVersion
+	default:
+		return nil, "", nil
+	}
+}
+
+// CheckDockerVersion checks if the docker daemon running on the host reports a supported version.
+func CheckDockerVersion(versionString string) State {
+	return checkRuntimeVersion(Docker, versionString)
+}
+
+// CheckContainerdVersion checks if the containerd daemon running on the host reports a supported version.
+func CheckContainerdVersion(versionString string) State {
+	return checkRuntimeVersion(Containerd, versionString)
+}
+
+// CheckPodmanVersion checks if the podman daemon running on the host reports a supported version.
+func CheckPodmanVersion(versionString string) State {
+	return checkRuntimeVersion(Podman, versionString)
+}
+
+// checkRuntimeVersion parses a "<os>-<version>" string (as reported by the runtime's info endpoint)
+// and compares it against the minimum version required by KIC for runtime.
+func checkRuntimeVersion(runtime, versionString string) State {
+	osType := versionString
+	version := ""
+	if idx := strings.Index(versionString, "-"); idx >= 0 {
+		osType = versionString[:idx]
+		version = versionString[idx+1:]
+	}
+
+	if osType == "windows" {
+		reason := fmt.Sprintf("PROVIDER_%s_WINDOWS_CONTAINERS", strings.ToUpper(runtime))
+		fix := fmt.Sprintf("Configure %s to use the Linux containers", runtimeTitle(runtime))
+		return State{
+			Installed: true,
+			Error:     ErrWindowsContainers,
+			Reason:    reason,
+			Fix:       fix,
+			Diagnostic: &Diagnostic{
+				Code:           reason,
+				Runtime:        runtime,
+				Observed:       osType,
+				Recommendation: fix,
+				DocsURL:        diagnosticDocsURL(runtime),
+			},
+		}
+	}
+
+	// "dev" is set when the runtime was installed from a source build (e.g. `make binary && make install`)
+	// "library-import" is set when the runtime was built directly with `go build` against the daemon package.
+	if version == "dev" || version == "library-import" {
+		return unknownVersionState(runtime, version)
+	}
+
+	if runtime == Docker {
+		return checkDockerVersionString(version)
+	}
+
+	return checkLegacyVersionString(runtime, version)
+}
+
+// unknownVersionState is returned whenever a version string can't be meaningfully compared against the
+// minimum required version — the daemon is assumed healthy, but the user is nudged towards an official release.
+func unknownVersionState(runtime, version string) State {
+	minVersion, _, _ := minVersionFor(runtime)
+	required := fmt.Sprintf("%02d.%02d.%d", minVersion[0], minVersion[1], minVersion[2])
+	fix := fmt.Sprintf("Install the official release of %s (Minimum recommended version is %s, current version is %s)",
+		runtimeTitle(runtime), required, version)
+	return State{
+		Installed: true,
+		Healthy:   true,
+		Fix:       fix,
+		Diagnostic: &Diagnostic{
+			Code:           "PROVIDER_UNKNOWN_VERSION",
+			Runtime:        runtime,
+			Observed:       version,
+			Required:       required,
+			Recommendation: fix,
+			DocsURL:        diagnosticDocsURL(runtime),
+		},
+	}
+}
+
+// checkLegacyVersionString implements the manual "%02d.%02d.%d" triplet comparison still used for
+// runtimes that don't (yet) report a semver-compliant version string, such as containerd and podman.
+func checkLegacyVersionString(runtime, version string) State {
+	minVersion, lowReason, lowErr := minVersionFor(runtime)
+
+	var major, minor, patch int
+	n, err := fmt.Sscanf(version, "%02d.%02d.%d", &major, &minor, &patch)
+	if err != nil && n < 2 {
+		return unknownVersionState(runtime, version)
+	}
+
+	if major < minVersion[0] ||
+		(major == minVersion[0] && minor < minVersion[1]) ||
+		(major == minVersion[0] && minor == minVersion[1] && patch < minVersion[2]) {
+		required := fmt.Sprintf("%d.%d.%d", minVersion[0], minVersion[1], minVersion[2])
+		observed := fmt.Sprintf("%d.%d.%d", major, minor, patch)
+		fix := fmt.Sprintf("Upgrade %s to a newer version (required: %s, found: %s)", runtimeTitle(runtime), required, observed)
+		return State{
+			Installed: true,
+			Error:     lowErr,
+			Reason:    lowReason,
+			Fix:       fix,
+			Diagnostic: &Diagnostic{
+				Code:           lowReason,
+				Runtime:        runtime,
+				Observed:       observed,
+				Required:       required,
+				Recommendation: fix,
+				DocsURL:        diagnosticDocsURL(runtime),
+			},
+		}
+	}
+
+	return State{Installed: true, Healthy: true, Running: true}
+}
+
+// parseDockerVersion parses a docker server version string (e.g. "20.10.0", "20.10.0-rc1",
+// "20.10.0-beta.3", or the git-describe-suffixed version reported by `make binary` installs) into a
+// semver.Version, reporting whether it is a prerelease and whether it carries a local-build suffix.
+func parseDockerVersion(s string) (v semver.Version, prerelease bool, localBuild bool, err error) {
+	if parts := strings.SplitN(s, "-", 2); len(parts) == 2 && gitDescribeSuffix.MatchString(parts[1]) {
+		localBuild = true
+		s = parts[0]
+	}
+
+	v, err = semver.ParseTolerant(s)
+	if err != nil {
+		return semver.Version{}, false, false, err
+	}
+
+	return v, len(v.Pre) > 0, localBuild, nil
+}
+
+// checkDockerVersionString implements the docker version comparison, using semver so that prerelease
+// and git-describe style version strings are handled correctly instead of being silently waved through.
+func checkDockerVersionString(version string) State {
+	v, prerelease, _, err := parseDockerVersion(version)
+	if err != nil {
+		return unknownVersionState(Docker, version)
+	}
+
+	min := semver.Version{Major: uint64(MinDockerVersion[0]), Minor: uint64(MinDockerVersion[1]), Patch: uint64(MinDockerVersion[2])}
+	if v.LT(min) {
+		fix := fmt.Sprintf("Upgrade %s to a newer version (required: %s, found: %s)", runtimeTitle(Docker), min, v)
+		return State{
+			Installed: true,
+			Error:     ErrMinDockerVersion,
+			Reason:    "PROVIDER_DOCKER_VERSION_LOW",
+			Fix:       fix,
+			Diagnostic: &Diagnostic{
+				Code:           "PROVIDER_DOCKER_VERSION_LOW",
+				Runtime:        Docker,
+				Observed:       v.String(),
+				Required:       min.String(),
+				Recommendation: fix,
+				DocsURL:        diagnosticDocsURL(Docker),
+			},
+		}
+	}
+
+	for _, r := range dockerBadVersions {
+		if r.contains(v) {
+			fix := fmt.Sprintf("%s %s is known to be broken (%s); upgrade or downgrade outside of the blocked range %s-%s",
+				runtimeTitle(Docker), v, r.reason, r.min, r.max)
+			return State{
+				Installed: true,
+				Error:     ErrBadDockerVersion,
+				Reason:    "PROVIDER_DOCKER_VERSION_BLOCKED",
+				Fix:       fix,
+				Diagnostic: &Diagnostic{
+					Code:           "PROVIDER_DOCKER_VERSION_BLOCKED",
+					Runtime:        Docker,
+					Observed:       v.String(),
+					Required:       fmt.Sprintf("outside %s-%s", r.min, r.max),
+					Recommendation: fix,
+					DocsURL:        diagnosticDocsURL(Docker),
+				},
+			}
+		}
+	}
+
+	if prerelease && !viper.GetBool("force") {
+		fix := fmt.Sprintf("%s %s is a prerelease version. Use --force to continue anyway, or install a stable release",
+			runtimeTitle(Docker), v)
+		return State{
+			Installed: true,
+			Healthy:   true,
+			Reason:    "PROVIDER_DOCKER_PRERELEASE",
+			Fix:       fix,
+			Diagnostic: &Diagnostic{
+				Code:           "PROVIDER_DOCKER_PRERELEASE",
+				Runtime:        Docker,
+				Observed:       v.String(),
+				Recommendation: fix,
+				DocsURL:        diagnosticDocsURL(Docker),
+			},
+		}
+	}
+
+	return State{Installed: true, Healthy: true, Running: true}
+}
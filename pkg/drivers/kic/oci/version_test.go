@@ -0,0 +1,311 @@
+/*
+Copyright 2019 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+type runtimeTestCase struct {
+	runtime           string
+	version           string
+	expectReason      string
+	expectError       error
+	expectFixContains string
+}
+
+func appendRuntimeVersionVariations(tc []runtimeTestCase, runtime string, v []int, reason string, err error) []runtimeTestCase {
+	appendedTc := append(tc, runtimeTestCase{
+		runtime:      runtime,
+		version:      fmt.Sprintf("linux-%02d.%02d", v[0], v[1]),
+		expectReason: reason,
+		expectError:  err,
+	})
+
+	// postfix string for unstable channel or patch builds.
+	patchPostFix := "20180720214833-f61e0f7"
+
+	vs := fmt.Sprintf("%02d.%02d.%d", v[0], v[1], v[2])
+	appendedTc = append(appendedTc, []runtimeTestCase{
+		{
+			runtime:      runtime,
+			version:      fmt.Sprintf("linux-%s", vs),
+			expectReason: reason,
+			expectError:  err,
+		},
+		{
+			runtime:      runtime,
+			version:      fmt.Sprintf("linux-%s-%s", vs, patchPostFix),
+			expectReason: reason,
+			expectError:  err,
+		},
+	}...,
+	)
+
+	return appendedTc
+}
+
+func TestCheckRuntimeVersion(t *testing.T) {
+	var tc []runtimeTestCase
+
+	runtimes := []struct {
+		name       string
+		minVersion []int
+		lowReason  string
+		lowErr     error
+	}{
+		{Docker, MinDockerVersion, "PROVIDER_DOCKER_VERSION_LOW", ErrMinDockerVersion},
+		{Containerd, MinContainerdVersion, "PROVIDER_CONTAINERD_VERSION_LOW", ErrMinContainerdVersion},
+		{Podman, MinPodmanVersion, "PROVIDER_PODMAN_VERSION_LOW", ErrMinPodmanVersion},
+	}
+
+	for _, rt := range runtimes {
+		tc = append(tc,
+			runtimeTestCase{
+				runtime:      rt.name,
+				version:      fmt.Sprintf("windows-%02d.%02d.%d", rt.minVersion[0], rt.minVersion[1], rt.minVersion[2]),
+				expectReason: fmt.Sprintf("PROVIDER_%s_WINDOWS_CONTAINERS", strings.ToUpper(rt.name)),
+				expectError:  ErrWindowsContainers,
+			},
+			runtimeTestCase{
+				runtime:      rt.name,
+				version:      fmt.Sprintf("linux-%02d.%02d", rt.minVersion[0], rt.minVersion[1]),
+				expectReason: "",
+				expectError:  nil,
+			},
+			runtimeTestCase{
+				runtime:      rt.name,
+				version:      fmt.Sprintf("linux-%02d.%02d.%02d", rt.minVersion[0], rt.minVersion[1], rt.minVersion[2]),
+				expectReason: "",
+				expectError:  nil,
+			},
+		)
+
+		for i := 0; i < 3; i++ {
+			v := make([]int, 3)
+			copy(v, rt.minVersion)
+
+			v[i] = rt.minVersion[i] + 1
+			tc = appendRuntimeVersionVariations(tc, rt.name, v, "", nil)
+
+			v[i] = rt.minVersion[i] - 1
+			if v[2] < 0 {
+				// skip test if patch version is negative number.
+				continue
+			}
+			tc = appendRuntimeVersionVariations(tc, rt.name, v, rt.lowReason, rt.lowErr)
+		}
+
+		tc = append(tc, []runtimeTestCase{
+			{
+				// "dev" is set when the runtime was installed via a source build.
+				runtime:      rt.name,
+				version:      "linux-dev",
+				expectReason: "",
+				expectError:  nil,
+				expectFixContains: fmt.Sprintf("Install the official release of %s (Minimum recommended version is %02d.%02d.%d, current version is dev)",
+					runtimeTitle(rt.name), rt.minVersion[0], rt.minVersion[1], rt.minVersion[2]),
+			},
+			{
+				// "library-import" is set when the runtime daemon was built directly via `go build`.
+				runtime:      rt.name,
+				version:      "linux-library-import",
+				expectReason: "",
+				expectError:  nil,
+				expectFixContains: fmt.Sprintf("Install the official release of %s (Minimum recommended version is %02d.%02d.%d, current version is library-import)",
+					runtimeTitle(rt.name), rt.minVersion[0], rt.minVersion[1], rt.minVersion[2]),
+			},
+			{
+				// "foo.bar.baz" is a triplet that cannot be parsed as "%02d.%02d.%d"
+				runtime:      rt.name,
+				version:      "linux-foo.bar.baz",
+				expectReason: "",
+				expectError:  nil,
+				expectFixContains: fmt.Sprintf("Install the official release of %s (Minimum recommended version is %02d.%02d.%d, current version is foo.bar.baz)",
+					runtimeTitle(rt.name), rt.minVersion[0], rt.minVersion[1], rt.minVersion[2]),
+			},
+		}...)
+	}
+
+	for _, c := range tc {
+		t.Run(fmt.Sprintf("%s/%s", c.runtime, c.version), func(t *testing.T) {
+			s := checkRuntimeVersion(c.runtime, c.version)
+			if c.expectReason != s.Reason {
+				t.Errorf("Reason %v expected. but got %q. (runtime: %s, version string: %s)", c.expectReason, s.Reason, c.runtime, c.version)
+			}
+			if s.Error != nil {
+				if c.expectError != s.Error {
+					t.Errorf("Error %v expected. but got %q. (runtime: %s, version string: %s)", c.expectError, s.Error, c.runtime, c.version)
+				}
+			}
+			if c.expectFixContains != "" {
+				if !strings.Contains(s.Fix, c.expectFixContains) {
+					t.Errorf("expected Fix to contain %q, but got %q", c.expectFixContains, s.Fix)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDockerVersion(t *testing.T) {
+	tc := []struct {
+		name               string
+		version            string
+		expectPrerelease   bool
+		expectLocalBuild   bool
+		expectErr          bool
+		expectLessThanBase bool // whether the parsed version orders before the 20.10.0 base release
+	}{
+		{name: "stable release", version: "20.10.0", expectPrerelease: false, expectLocalBuild: false},
+		{name: "RC build", version: "20.10.0-rc1", expectPrerelease: true, expectLessThanBase: true},
+		{name: "nightly/beta tag", version: "20.10.0-beta.3", expectPrerelease: true, expectLessThanBase: true},
+		{name: "git-describe local build suffix", version: "20.10.0-20180720214833-f61e0f7", expectPrerelease: false, expectLocalBuild: true},
+		{name: "malformed triplet", version: "foo.bar.baz", expectErr: true},
+	}
+
+	base := semver.MustParse("20.10.0")
+
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			v, prerelease, localBuild, err := parseDockerVersion(c.version)
+			if c.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error parsing %q, got none", c.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", c.version, err)
+			}
+			if prerelease != c.expectPrerelease {
+				t.Errorf("prerelease: expected %v, got %v", c.expectPrerelease, prerelease)
+			}
+			if localBuild != c.expectLocalBuild {
+				t.Errorf("localBuild: expected %v, got %v", c.expectLocalBuild, localBuild)
+			}
+			if c.expectLessThanBase && !v.LT(base) {
+				t.Errorf("expected %s to sort before %s (prerelease ordering)", v, base)
+			}
+		})
+	}
+}
+
+func TestCheckDockerVersionPrerelease(t *testing.T) {
+	s := checkDockerVersionString("20.10.0-rc1")
+	if s.Reason != "PROVIDER_DOCKER_PRERELEASE" {
+		t.Errorf("expected Reason PROVIDER_DOCKER_PRERELEASE, got %q", s.Reason)
+	}
+	if s.Error != nil {
+		t.Errorf("expected no Error for a prerelease without --force, got %v", s.Error)
+	}
+	if !strings.Contains(s.Fix, "prerelease") {
+		t.Errorf("expected Fix to mention the prerelease, got %q", s.Fix)
+	}
+	if s.Diagnostic == nil || s.Diagnostic.Code != "PROVIDER_DOCKER_PRERELEASE" {
+		t.Errorf("expected Diagnostic.Code PROVIDER_DOCKER_PRERELEASE, got %+v", s.Diagnostic)
+	}
+}
+
+func TestParseDockerBadVersions(t *testing.T) {
+	tc := []struct {
+		raw    string
+		expect []versionRange
+	}{
+		{raw: "", expect: nil},
+		{
+			raw: "20.10.9:20.10.10,19.03.13:19.03.13",
+			expect: []versionRange{
+				{min: mustParseTolerant("20.10.9"), max: mustParseTolerant("20.10.10")},
+				{min: mustParseTolerant("19.03.13"), max: mustParseTolerant("19.03.13")},
+			},
+		},
+		{raw: "not-a-range", expect: nil},
+	}
+
+	for _, c := range tc {
+		t.Run(c.raw, func(t *testing.T) {
+			got := parseDockerBadVersions(c.raw)
+			if len(got) != len(c.expect) {
+				t.Fatalf("expected %d ranges, got %d: %+v", len(c.expect), len(got), got)
+			}
+			for i, r := range got {
+				if !r.min.EQ(c.expect[i].min) || !r.max.EQ(c.expect[i].max) {
+					t.Errorf("range %d: expected %s-%s, got %s-%s", i, c.expect[i].min, c.expect[i].max, r.min, r.max)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDockerVersionBlocklist(t *testing.T) {
+	tc := []struct {
+		name         string
+		version      string
+		expectReason string
+		expectError  error
+	}{
+		{name: "just below the blocked range", version: "linux-19.03.12", expectReason: "", expectError: nil},
+		{name: "inside a single-version blocked range", version: "linux-19.03.13", expectReason: "PROVIDER_DOCKER_VERSION_BLOCKED", expectError: ErrBadDockerVersion},
+		{name: "lower boundary of a blocked range", version: "linux-20.10.9", expectReason: "PROVIDER_DOCKER_VERSION_BLOCKED", expectError: ErrBadDockerVersion},
+		{name: "upper boundary of a blocked range", version: "linux-20.10.10", expectReason: "PROVIDER_DOCKER_VERSION_BLOCKED", expectError: ErrBadDockerVersion},
+		{name: "just above the blocked range", version: "linux-20.10.11", expectReason: "", expectError: nil},
+	}
+
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			s := checkDockerVersionString(strings.TrimPrefix(c.version, "linux-"))
+			if s.Reason != c.expectReason {
+				t.Errorf("Reason %v expected, but got %q", c.expectReason, s.Reason)
+			}
+			if s.Error != c.expectError {
+				t.Errorf("Error %v expected, but got %v", c.expectError, s.Error)
+			}
+			if c.expectReason == "PROVIDER_DOCKER_VERSION_BLOCKED" {
+				if s.Diagnostic == nil || s.Diagnostic.Code != "PROVIDER_DOCKER_VERSION_BLOCKED" {
+					t.Errorf("expected Diagnostic.Code PROVIDER_DOCKER_VERSION_BLOCKED, got %+v", s.Diagnostic)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderDiagnosticsJSON(t *testing.T) {
+	healthy := checkRuntimeVersion(Docker, fmt.Sprintf("linux-%02d.%02d.%02d", MinDockerVersion[0], MinDockerVersion[1], MinDockerVersion[2]))
+	low := checkRuntimeVersion(Docker, "linux-01.01.01")
+
+	out, err := RenderDiagnosticsJSON(healthy, low)
+	if err != nil {
+		t.Fatalf("RenderDiagnosticsJSON returned error: %v", err)
+	}
+
+	var diags []Diagnostic
+	if err := json.Unmarshal(out, &diags); err != nil {
+		t.Fatalf("failed to unmarshal diagnostics: %v", err)
+	}
+
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic (healthy state should be omitted), got %d: %+v", len(diags), diags)
+	}
+	if diags[0].Code != "PROVIDER_DOCKER_VERSION_LOW" {
+		t.Errorf("expected code PROVIDER_DOCKER_VERSION_LOW, got %q", diags[0].Code)
+	}
+}
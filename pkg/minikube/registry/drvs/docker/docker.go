@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"fmt"
+
+	"k8s.io/minikube/pkg/drivers/kic/oci"
+)
+
+// minDockerVersion is the minimum version of docker supported by KIC.
+var minDockerVersion = oci.MinDockerVersion
+
+// checkDockerVersion reports the health of the docker daemon identified by versionString, which is
+// expected to be of the form "<os type>-<server version>" as reported by the docker info endpoint.
+func checkDockerVersion(versionString string) oci.State {
+	return oci.CheckDockerVersion(versionString)
+}
+
+// StatusJSON returns the structured Diagnostic for the docker daemon identified by versionString,
+// serialized for the preflight subsystem's `--output=json` consumers (CI, IDE plugins, the VS Code
+// Kubernetes extension). See cmd/minikube/cmd/preflight.go for the CLI entry point that calls this.
+func StatusJSON(versionString string) ([]byte, error) {
+	return oci.RenderDiagnosticsJSON(checkDockerVersion(versionString))
+}
+
+// StatusText renders a one-line human-readable preflight summary for versionString, for the
+// preflight subsystem's default (non `--output=json`) text path.
+func StatusText(versionString string) string {
+	s := checkDockerVersion(versionString)
+	if s.Healthy && s.Reason == "" {
+		return fmt.Sprintf("docker %s: OK", versionString)
+	}
+	return fmt.Sprintf("docker %s: %s (%s)", versionString, s.Reason, s.Fix)
+}
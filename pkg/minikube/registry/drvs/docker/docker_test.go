@@ -17,6 +17,7 @@ limitations under the License.
 package docker
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -30,6 +31,7 @@ type testCase struct {
 	expectReason      string
 	expectError       error
 	expectFixContains string
+	expectDiagnostic  string
 }
 
 func appendVersionVariations(tc []testCase, v []int, reason string, err error) []testCase {
@@ -63,9 +65,10 @@ func appendVersionVariations(tc []testCase, v []int, reason string, err error) [
 func TestCheckDockerVersion(t *testing.T) {
 	tc := []testCase{
 		{
-			version:      "windows-20.0.1",
-			expectReason: "PROVIDER_DOCKER_WINDOWS_CONTAINERS",
-			expectError:  oci.ErrWindowsContainers,
+			version:          "windows-20.0.1",
+			expectReason:     "PROVIDER_DOCKER_WINDOWS_CONTAINERS",
+			expectError:      oci.ErrWindowsContainers,
+			expectDiagnostic: "PROVIDER_DOCKER_WINDOWS_CONTAINERS",
 		},
 		{
 			version:      fmt.Sprintf("linux-%02d.%02d", minDockerVersion[0], minDockerVersion[1]),
@@ -93,6 +96,11 @@ func TestCheckDockerVersion(t *testing.T) {
 		}
 		tc = appendVersionVariations(tc, v, "PROVIDER_DOCKER_VERSION_LOW", oci.ErrMinDockerVersion)
 	}
+	for i := range tc {
+		if tc[i].expectReason == "PROVIDER_DOCKER_VERSION_LOW" {
+			tc[i].expectDiagnostic = "PROVIDER_DOCKER_VERSION_LOW"
+		}
+	}
 
 	tc = append(tc, []testCase{
 		{
@@ -102,6 +110,7 @@ func TestCheckDockerVersion(t *testing.T) {
 			expectError:  nil,
 			expectFixContains: fmt.Sprintf("Install the official release of %s (Minimum recommended version is %02d.%02d.%d, current version is dev)",
 				driver.FullName(driver.Docker), minDockerVersion[0], minDockerVersion[1], minDockerVersion[2]),
+			expectDiagnostic: "PROVIDER_UNKNOWN_VERSION",
 		},
 		{
 			// "library-import" is set when Docker (Moby) was installed with `go build github.com/docker/docker/cmd/dockerd` (unrecommended, but valid)
@@ -110,6 +119,7 @@ func TestCheckDockerVersion(t *testing.T) {
 			expectError:  nil,
 			expectFixContains: fmt.Sprintf("Install the official release of %s (Minimum recommended version is %02d.%02d.%d, current version is library-import)",
 				driver.FullName(driver.Docker), minDockerVersion[0], minDockerVersion[1], minDockerVersion[2]),
+			expectDiagnostic: "PROVIDER_UNKNOWN_VERSION",
 		},
 		{
 			// "foo.bar.baz" is a triplet that cannot be parsed as "%02d.%02d.%d"
@@ -118,6 +128,71 @@ func TestCheckDockerVersion(t *testing.T) {
 			expectError:  nil,
 			expectFixContains: fmt.Sprintf("Install the official release of %s (Minimum recommended version is %02d.%02d.%d, current version is foo.bar.baz)",
 				driver.FullName(driver.Docker), minDockerVersion[0], minDockerVersion[1], minDockerVersion[2]),
+			expectDiagnostic: "PROVIDER_UNKNOWN_VERSION",
+		},
+		{
+			// RC builds should be recognized as a valid, but unsupported-by-default, prerelease.
+			version:           "linux-20.10.0-rc1",
+			expectReason:      "PROVIDER_DOCKER_PRERELEASE",
+			expectError:       nil,
+			expectFixContains: "is a prerelease version",
+			expectDiagnostic:  "PROVIDER_DOCKER_PRERELEASE",
+		},
+		{
+			// nightly-style prerelease tags are handled the same way as RC builds.
+			version:           "linux-20.10.0-beta.3",
+			expectReason:      "PROVIDER_DOCKER_PRERELEASE",
+			expectError:       nil,
+			expectFixContains: "is a prerelease version",
+			expectDiagnostic:  "PROVIDER_DOCKER_PRERELEASE",
+		},
+		{
+			// git-describe style suffixes used by `make binary` installs are local build metadata, not a prerelease.
+			version:      "linux-20.10.0-20180720214833-f61e0f7",
+			expectReason: "",
+			expectError:  nil,
+		},
+		{
+			// malformed version that isn't valid semver at all.
+			version:      "linux-20.ten.0",
+			expectReason: "",
+			expectError:  nil,
+			expectFixContains: fmt.Sprintf("Install the official release of %s (Minimum recommended version is %02d.%02d.%d, current version is 20.ten.0)",
+				driver.FullName(driver.Docker), minDockerVersion[0], minDockerVersion[1], minDockerVersion[2]),
+			expectDiagnostic: "PROVIDER_UNKNOWN_VERSION",
+		},
+		{
+			// just below the blocked range (19.03.13 is a single-version block for an iptables-nft regression).
+			version:      "linux-19.03.12",
+			expectReason: "",
+			expectError:  nil,
+		},
+		{
+			// inside the blocked range.
+			version:          "linux-19.03.13",
+			expectReason:     "PROVIDER_DOCKER_VERSION_BLOCKED",
+			expectError:      oci.ErrBadDockerVersion,
+			expectDiagnostic: "PROVIDER_DOCKER_VERSION_BLOCKED",
+		},
+		{
+			// lower boundary of the blocked cgroup v2 range.
+			version:          "linux-20.10.9",
+			expectReason:     "PROVIDER_DOCKER_VERSION_BLOCKED",
+			expectError:      oci.ErrBadDockerVersion,
+			expectDiagnostic: "PROVIDER_DOCKER_VERSION_BLOCKED",
+		},
+		{
+			// upper boundary of the blocked cgroup v2 range.
+			version:          "linux-20.10.10",
+			expectReason:     "PROVIDER_DOCKER_VERSION_BLOCKED",
+			expectError:      oci.ErrBadDockerVersion,
+			expectDiagnostic: "PROVIDER_DOCKER_VERSION_BLOCKED",
+		},
+		{
+			// just above the blocked range.
+			version:      "linux-20.10.11",
+			expectReason: "",
+			expectError:  nil,
 		},
 	}...)
 
@@ -137,6 +212,38 @@ func TestCheckDockerVersion(t *testing.T) {
 					t.Errorf("Error expected Fix to contain %q, but got %q", c.expectFixContains, s.Fix)
 				}
 			}
+			if c.expectDiagnostic != "" {
+				if s.Diagnostic == nil || s.Diagnostic.Code != c.expectDiagnostic {
+					t.Errorf("expected Diagnostic.Code %q, but got %+v", c.expectDiagnostic, s.Diagnostic)
+				}
+			}
 		})
 	}
 }
+
+func TestStatusJSON(t *testing.T) {
+	out, err := StatusJSON("linux-01.01.01")
+	if err != nil {
+		t.Fatalf("StatusJSON returned error: %v", err)
+	}
+
+	var diags []oci.Diagnostic
+	if jsonErr := json.Unmarshal(out, &diags); jsonErr != nil {
+		t.Fatalf("StatusJSON did not return valid JSON: %v (%s)", jsonErr, out)
+	}
+	if len(diags) != 1 || diags[0].Code != "PROVIDER_DOCKER_VERSION_LOW" {
+		t.Errorf("expected a single PROVIDER_DOCKER_VERSION_LOW diagnostic, got %+v", diags)
+	}
+
+	out, err = StatusJSON(fmt.Sprintf("linux-%02d.%02d.%02d", minDockerVersion[0], minDockerVersion[1], minDockerVersion[2]))
+	if err != nil {
+		t.Fatalf("StatusJSON returned error: %v", err)
+	}
+	diags = nil
+	if jsonErr := json.Unmarshal(out, &diags); jsonErr != nil {
+		t.Fatalf("StatusJSON did not return valid JSON: %v (%s)", jsonErr, out)
+	}
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics for a healthy version, got %+v", diags)
+	}
+}